@@ -0,0 +1,126 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command atlas-osb-k8s-proxy runs the k8sproxy reconcile loop as a
+// standalone process against an already-running atlas-osb broker, for
+// deployments that don't want client-go linked into the broker binary
+// itself. It polls the broker's /v2/catalog endpoint and keeps a
+// ClusterServiceBroker (plus its credentials Secret) in sync with it.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/k8sproxy"
+	"github.com/pivotal-cf/brokerapi/domain"
+	"go.uber.org/zap"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func main() {
+	brokerURL := flag.String("broker-url", os.Getenv("BROKER_URL"), "externally reachable URL of the atlas-osb broker")
+	brokerName := flag.String("broker-name", "atlas-osb", "name of the ClusterServiceBroker object to reconcile")
+	namespace := flag.String("namespace", "default", "namespace for the broker credentials Secret")
+	secretName := flag.String("secret-name", "atlas-osb-credentials", "name of the broker credentials Secret")
+	username := flag.String("broker-username", os.Getenv("BROKER_USERNAME"), "Basic auth username service-catalog should use against the broker")
+	password := flag.String("broker-password", os.Getenv("BROKER_PASSWORD"), "Basic auth password service-catalog should use against the broker")
+	pollInterval := flag.Duration("poll-interval", 30*time.Second, "how often to fetch the broker's catalog and reconcile")
+	flag.Parse()
+
+	logger := zap.NewExample().Sugar()
+	defer logger.Sync() //nolint:errcheck
+
+	if *brokerURL == "" {
+		logger.Fatal("-broker-url (or BROKER_URL) is required")
+	}
+
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		logger.Fatalw("cannot load in-cluster Kubernetes config", "err", err)
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		logger.Fatalw("cannot create Kubernetes client", "err", err)
+	}
+
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		logger.Fatalw("cannot create Kubernetes dynamic client", "err", err)
+	}
+
+	reconciler := k8sproxy.New(k8sproxy.Config{
+		BrokerName: *brokerName,
+		BrokerURL:  *brokerURL,
+		Namespace:  *namespace,
+		SecretName: *secretName,
+		Credentials: k8sproxy.Credentials{
+			Username: *username,
+			Password: *password,
+		},
+	}, client, dyn)
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	ticker := time.NewTicker(*pollInterval)
+	defer ticker.Stop()
+
+	for {
+		services, err := fetchCatalog(httpClient, *brokerURL, *username, *password)
+		if err != nil {
+			logger.Errorw("cannot fetch broker catalog", "err", err)
+		} else if err := reconciler.Reconcile(context.Background(), services); err != nil {
+			logger.Errorw("reconcile failed", "err", err)
+		}
+
+		<-ticker.C
+	}
+}
+
+func fetchCatalog(client *http.Client, brokerURL, username, password string) ([]domain.Service, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v2/catalog", brokerURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("broker returned status %d fetching catalog", resp.StatusCode)
+	}
+
+	var catalog struct {
+		Services []domain.Service `json:"services"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&catalog); err != nil {
+		return nil, err
+	}
+
+	return catalog.Services, nil
+}
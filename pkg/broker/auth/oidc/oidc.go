@@ -0,0 +1,178 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oidc validates OIDC-issued bearer tokens against a discovered
+// JWKS, the way Peripli's service-manager security/oidc package does for
+// service-manager. It is an alternative to HTTP Basic auth for platforms
+// that already run an identity provider (Dex, Keycloak, Azure AD, ...).
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Config configures an Authenticator.
+type Config struct {
+	// IssuerURL is the OIDC issuer; its .well-known/openid-configuration
+	// document is used to discover the JWKS endpoint.
+	IssuerURL string
+
+	// Audience is the expected "aud" claim, typically the broker's OAuth
+	// client ID.
+	Audience string
+
+	// RequiredScopes, if non-empty, must all be present in the token's
+	// "scope" claim (space-separated, per RFC 6749) for the request to be
+	// authorized.
+	RequiredScopes []string
+
+	// JWKSRefreshInterval controls how often the signing keys are
+	// re-fetched in the background. Defaults to 1 hour.
+	JWKSRefreshInterval time.Duration
+}
+
+// Authenticator validates bearer tokens issued by Config.IssuerURL.
+type Authenticator struct {
+	logger *zap.SugaredLogger
+	cfg    Config
+	keySet *keySet
+}
+
+// New discovers the issuer's JWKS endpoint and starts an Authenticator that
+// keeps the signing keys fresh in the background. Call Close to stop the
+// refresh loop.
+func New(ctx context.Context, logger *zap.SugaredLogger, cfg Config) (*Authenticator, error) {
+	if cfg.IssuerURL == "" {
+		return nil, errors.New("oidc: IssuerURL is required")
+	}
+	if cfg.JWKSRefreshInterval <= 0 {
+		cfg.JWKSRefreshInterval = time.Hour
+	}
+
+	jwksURI, err := discoverJWKSURI(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot discover JWKS endpoint")
+	}
+
+	ks, err := newKeySet(ctx, jwksURI, cfg.JWKSRefreshInterval)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot fetch JWKS")
+	}
+
+	return &Authenticator{logger: logger, cfg: cfg, keySet: ks}, nil
+}
+
+// Close stops the background JWKS refresh.
+func (a *Authenticator) Close() {
+	a.keySet.Close()
+}
+
+// Middleware returns a mux.MiddlewareFunc that validates the
+// "Authorization: Bearer <token>" header before passing the request
+// through. Requests without a valid token receive a 401; requests missing
+// a required scope receive a 403.
+func (a *Authenticator) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := a.authenticate(r)
+			if err != nil {
+				if errors.Is(err, errInsufficientScope) {
+					http.Error(w, err.Error(), http.StatusForbidden)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if a.logger != nil {
+				a.logger.Debugw("authenticated OIDC request", "subject", claims.Subject)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var errInsufficientScope = errors.New("token is missing a required scope")
+
+type claims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope"`
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*claims, error) {
+	raw, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &claims{}
+	_, err = jwt.ParseWithClaims(raw, c, a.keySet.Keyfunc, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bearer token")
+	}
+
+	if !containsString(c.Audience, a.cfg.Audience) {
+		return nil, fmt.Errorf("token audience does not include %q", a.cfg.Audience)
+	}
+	if c.Issuer != a.cfg.IssuerURL {
+		return nil, fmt.Errorf("token issuer does not match %q", a.cfg.IssuerURL)
+	}
+
+	if len(a.cfg.RequiredScopes) > 0 && !hasAllScopes(c.Scope, a.cfg.RequiredScopes) {
+		return nil, errInsufficientScope
+	}
+
+	return c, nil
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return "", errors.New("missing bearer token")
+	}
+	return strings.TrimPrefix(header, prefix), nil
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAllScopes(scopeClaim string, required []string) bool {
+	granted := make(map[string]bool)
+	for _, s := range strings.Fields(scopeClaim) {
+		granted[s] = true
+	}
+
+	for _, want := range required {
+		if !granted[want] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func testContext() context.Context {
+	return context.Background()
+}
+
+// fakeIdP serves a minimal OIDC discovery document and JWKS endpoint, and
+// can mint tokens signed by whichever key is currently "active" so tests
+// can simulate key rotation.
+type fakeIdP struct {
+	server *httptest.Server
+	keys   map[string]*rsa.PrivateKey
+	active string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+
+	idp := &fakeIdP{keys: make(map[string]*rsa.PrivateKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"jwks_uri": idp.server.URL + "/jwks.json",
+		})
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		var doc jwksDoc
+		for kid, key := range idp.keys {
+			doc.Keys = append(doc.Keys, jwk{
+				Kid: kid,
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(doc)
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *fakeIdP) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %v", err)
+	}
+	idp.keys[kid] = key
+	idp.active = kid
+}
+
+func (idp *fakeIdP) removeKey(kid string) {
+	delete(idp.keys, kid)
+}
+
+func (idp *fakeIdP) token(t *testing.T, kid string, c claims) string {
+	t.Helper()
+
+	key, ok := idp.keys[kid]
+	if !ok {
+		t.Fatalf("no such key %q registered with fake IdP", kid)
+	}
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	tok.Header["kid"] = kid
+
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatalf("cannot sign token: %v", err)
+	}
+	return signed
+}
+
+func newTestClaims(issuer, audience string, expiry time.Time, scope string) claims {
+	return claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "test-subject",
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(expiry),
+		},
+		Scope: scope,
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.addKey(t, "key-1")
+
+	auth, err := New(testContext(), nil, Config{
+		IssuerURL:           idp.server.URL,
+		Audience:            "atlas-osb",
+		RequiredScopes:      []string{"broker:provision"},
+		JWKSRefreshInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(auth.Close)
+
+	tests := []struct {
+		name       string
+		token      func() string
+		wantStatus int
+	}{
+		{
+			name: "valid token",
+			token: func() string {
+				return idp.token(t, "key-1", newTestClaims(idp.server.URL, "atlas-osb", time.Now().Add(time.Hour), "broker:provision"))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				return idp.token(t, "key-1", newTestClaims(idp.server.URL, "atlas-osb", time.Now().Add(-time.Hour), "broker:provision"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				return idp.token(t, "key-1", newTestClaims(idp.server.URL, "some-other-service", time.Now().Add(time.Hour), "broker:provision"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "missing required scope",
+			token: func() string {
+				return idp.token(t, "key-1", newTestClaims(idp.server.URL, "atlas-osb", time.Now().Add(time.Hour), "broker:read"))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	handler := auth.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token())
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_rotatedKeys(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.addKey(t, "key-1")
+
+	auth, err := New(testContext(), nil, Config{
+		IssuerURL:           idp.server.URL,
+		Audience:            "atlas-osb",
+		JWKSRefreshInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(auth.Close)
+
+	handler := auth.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Rotate: retire key-1, introduce key-2, and wait for the background
+	// refresh to pick it up.
+	idp.removeKey("key-1")
+	idp.addKey(t, "key-2")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+		req.Header.Set("Authorization", "Bearer "+idp.token(t, "key-2", newTestClaims(idp.server.URL, "atlas-osb", time.Now().Add(time.Hour), "")))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code == http.StatusOK {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("token signed with rotated key was never accepted, last status %d", rec.Code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package autoscale adjusts Atlas cluster tier/shard count on a plan
+// before it's finalized, driven by how many provisions are currently
+// pending against it - borrowing the queue-length signal Selenium Grid's
+// scaler exposes for KEDA, applied here to OSB provision requests instead
+// of pod replicas.
+package autoscale
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// queueState tracks one plan key's in-flight provisions and a short
+// history of samples used to compute a moving average.
+type queueState struct {
+	pending int
+	samples []int
+}
+
+// Coordinator observes pending provisions per plan key and decides whether
+// to scale that plan up, via Policy. Construct with New; call IncPending
+// from Provision and DecPending from LastOperation once a provision
+// settles, and Adjust from wherever a plan is finalized (e.g. parsePlan).
+type Coordinator struct {
+	Policy ScalePolicy
+	// DryRun, when true, makes Adjust log and record the decision it
+	// would have made without actually changing the plan.
+	DryRun bool
+
+	logger *zap.SugaredLogger
+
+	mu     sync.Mutex
+	queues map[string]*queueState
+
+	sampleWindow int
+	metrics      *metrics
+	registry     *prometheus.Registry
+
+	stop chan struct{}
+}
+
+// New returns a Coordinator using policy to make scaling decisions, with
+// an N-sample moving average window. A nil logger disables logging.
+func New(policy ScalePolicy, sampleWindow int, dryRun bool, logger *zap.SugaredLogger) *Coordinator {
+	if sampleWindow <= 0 {
+		sampleWindow = 5
+	}
+
+	reg := prometheus.NewRegistry()
+
+	return &Coordinator{
+		Policy:       policy,
+		DryRun:       dryRun,
+		logger:       logger,
+		queues:       make(map[string]*queueState),
+		sampleWindow: sampleWindow,
+		metrics:      newMetrics(reg),
+		registry:     reg,
+		stop:         make(chan struct{}),
+	}
+}
+
+// IncPending records a new pending provision for key, e.g. targeted at a
+// given dp.Project.Name+plan combination.
+func (c *Coordinator) IncPending(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queueFor(key).pending++
+}
+
+// DecPending records that a pending provision for key has settled
+// (LastOperation reported success or failure).
+func (c *Coordinator) DecPending(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	q := c.queueFor(key)
+	if q.pending > 0 {
+		q.pending--
+	}
+}
+
+func (c *Coordinator) queueFor(key string) *queueState {
+	q, ok := c.queues[key]
+	if !ok {
+		q = &queueState{}
+		c.queues[key] = q
+	}
+	return q
+}
+
+// Sample takes one moving-average sample for every known key. Start runs
+// this on a ticker; tests can call it directly to avoid sleeping.
+func (c *Coordinator) Sample() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, q := range c.queues {
+		q.samples = append(q.samples, q.pending)
+		if len(q.samples) > c.sampleWindow {
+			q.samples = q.samples[len(q.samples)-c.sampleWindow:]
+		}
+		c.metrics.observePending(key, movingAverage(q.samples))
+	}
+}
+
+func movingAverage(samples []int) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, s := range samples {
+		sum += s
+	}
+	return float64(sum) / float64(len(samples))
+}
+
+// pendingAvg returns key's current moving average without taking a new
+// sample.
+func (c *Coordinator) pendingAvg(key string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return movingAverage(c.queueFor(key).samples)
+}
+
+// Adjust asks Policy whether key's plan should scale given its current
+// moving average, and returns the (possibly unchanged) tier/shard count to
+// use. In DryRun mode the decision is logged and recorded in metrics but
+// state.Tier/state.Shards are returned unchanged.
+func (c *Coordinator) Adjust(key string, state PlanState) Decision {
+	decision := c.Policy.Decide(c.pendingAvg(key), state)
+	if !decision.Scaled {
+		return decision
+	}
+
+	if c.logger != nil {
+		c.logger.Infow("autoscale decision",
+			"key", key, "from_tier", state.Tier, "to_tier", decision.Tier,
+			"from_shards", state.Shards, "to_shards", decision.Shards,
+			"dry_run", c.DryRun, "reason", decision.Reason)
+	}
+
+	c.metrics.recordDecision(key, state.Tier, decision.Tier)
+
+	if c.DryRun {
+		return Decision{Tier: state.Tier, Shards: state.Shards}
+	}
+	return decision
+}
+
+// Start runs Sample on interval until Stop is called.
+func (c *Coordinator) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.Sample()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background sampling loop started by Start.
+func (c *Coordinator) Stop() {
+	close(c.stop)
+}
+
+// Handler serves the coordinator's metrics in the Prometheus exposition
+// format.
+func (c *Coordinator) Handler() http.Handler {
+	return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+}
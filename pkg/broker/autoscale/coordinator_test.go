@@ -0,0 +1,107 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscale
+
+import "testing"
+
+// fakeProvisioner simulates a broker driving Provision/LastOperation
+// against a Coordinator: Provision increments the queue, LastOperation
+// success decrements it. It stands in for the real Broker, whose
+// Provision/LastOperation call into Atlas via *mongodbatlas.Client.
+type fakeProvisioner struct {
+	coord *Coordinator
+	key   string
+}
+
+func (f *fakeProvisioner) Provision() {
+	f.coord.IncPending(f.key)
+}
+
+func (f *fakeProvisioner) LastOperationSucceeded() {
+	f.coord.DecPending(f.key)
+}
+
+func TestCoordinator_scalesUpUnderSustainedLoad(t *testing.T) {
+	coord := New(ThresholdPolicy{Threshold: 2}, 3, false, nil)
+	fp := &fakeProvisioner{coord: coord, key: "myproject/M30"}
+
+	// Drive five concurrent provisions against the same plan key and let
+	// them sit pending across several samples, simulating a burst of
+	// incoming Provision calls that outpaces LastOperation completions.
+	for i := 0; i < 5; i++ {
+		fp.Provision()
+	}
+	coord.Sample()
+	coord.Sample()
+	coord.Sample()
+
+	decision := coord.Adjust(fp.key, PlanState{Tier: "M30", MaxTier: "M60"})
+	if !decision.Scaled {
+		t.Fatal("Adjust() did not scale under sustained pending load")
+	}
+	if decision.Tier != "M40" {
+		t.Errorf("decision.Tier = %q, want %q", decision.Tier, "M40")
+	}
+}
+
+func TestCoordinator_staysPutUnderLightLoad(t *testing.T) {
+	coord := New(ThresholdPolicy{Threshold: 2}, 3, false, nil)
+	fp := &fakeProvisioner{coord: coord, key: "myproject/M30"}
+
+	fp.Provision()
+	coord.Sample()
+	fp.LastOperationSucceeded()
+	coord.Sample()
+
+	decision := coord.Adjust(fp.key, PlanState{Tier: "M30", MaxTier: "M60"})
+	if decision.Scaled {
+		t.Fatalf("Adjust() scaled under light load: %+v", decision)
+	}
+	if decision.Tier != "M30" {
+		t.Errorf("decision.Tier = %q, want unchanged %q", decision.Tier, "M30")
+	}
+}
+
+func TestCoordinator_dryRunDoesNotChangeTier(t *testing.T) {
+	coord := New(ThresholdPolicy{Threshold: 1}, 2, true, nil)
+	fp := &fakeProvisioner{coord: coord, key: "myproject/M30"}
+
+	fp.Provision()
+	fp.Provision()
+	fp.Provision()
+	coord.Sample()
+	coord.Sample()
+
+	decision := coord.Adjust(fp.key, PlanState{Tier: "M30", MaxTier: "M60"})
+	if decision.Tier != "M30" {
+		t.Errorf("dry-run Adjust() changed tier to %q, want unchanged %q", decision.Tier, "M30")
+	}
+}
+
+func TestCoordinator_capsAtMaxTierThenAddsShards(t *testing.T) {
+	coord := New(ThresholdPolicy{Threshold: 0}, 1, false, nil)
+	fp := &fakeProvisioner{coord: coord, key: "myproject/M60"}
+
+	fp.Provision()
+	coord.Sample()
+
+	decision := coord.Adjust(fp.key, PlanState{Tier: "M60", MaxTier: "M60", Shards: 1, MaxShards: 3})
+	if decision.Tier != "M60" {
+		t.Errorf("decision.Tier = %q, want capped at MaxTier %q", decision.Tier, "M60")
+	}
+	if decision.Shards != 2 {
+		t.Errorf("decision.Shards = %d, want 2 after tier was capped", decision.Shards)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscale
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	pendingProvisions *prometheus.GaugeVec
+	scaleDecisions    *prometheus.CounterVec
+	currentTier       *prometheus.GaugeVec
+}
+
+// newMetrics creates the package's gauges/counters and registers them with
+// reg. Each Coordinator gets its own prometheus.Registry (rather than the
+// global default) so multiple Coordinators - e.g. one per test - can
+// coexist without colliding on metric names.
+func newMetrics(reg *prometheus.Registry) *metrics {
+	m := &metrics{
+		pendingProvisions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "atlas_osb_pending_provisions",
+			Help: "Moving average of pending provision requests per plan key.",
+		}, []string{"key"}),
+		scaleDecisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "atlas_osb_scale_decisions_total",
+			Help: "Count of autoscaling decisions made, by plan key and outcome.",
+		}, []string{"key", "from_tier", "to_tier"}),
+		currentTier: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "atlas_osb_current_tier",
+			Help: "1 for the tier a plan key is currently scaled to, 0 otherwise.",
+		}, []string{"key", "tier"}),
+	}
+
+	reg.MustRegister(m.pendingProvisions, m.scaleDecisions, m.currentTier)
+	return m
+}
+
+func (m *metrics) observePending(key string, avg float64) {
+	m.pendingProvisions.WithLabelValues(key).Set(avg)
+}
+
+func (m *metrics) recordDecision(key, fromTier, toTier string) {
+	m.scaleDecisions.WithLabelValues(key, fromTier, toTier).Inc()
+	if fromTier != toTier {
+		m.currentTier.WithLabelValues(key, fromTier).Set(0)
+	}
+	m.currentTier.WithLabelValues(key, toTier).Set(1)
+}
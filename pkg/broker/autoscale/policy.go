@@ -0,0 +1,85 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscale
+
+// PlanState is the subset of a parsed plan ScalePolicy needs to decide
+// whether to scale it, and the result of that decision. It's deliberately
+// narrow (rather than depending on dynamicplans.Plan directly) so policies
+// can be unit tested without constructing a full plan.
+type PlanState struct {
+	// Tier is the plan's current InstanceSizeName, e.g. "M30".
+	Tier string
+	// MaxTier caps how far Decide may scale Tier up, as set by the plan
+	// template's MaxTier annotation. Empty means uncapped.
+	MaxTier string
+	// Shards is the plan's current shard count.
+	Shards int
+	// MaxShards caps Shards, as set by the plan template's MaxNodes
+	// annotation. Zero means uncapped.
+	MaxShards int
+}
+
+// Decision is what a ScalePolicy wants to change about a plan.
+type Decision struct {
+	Tier   string
+	Shards int
+	Scaled bool
+	Reason string
+}
+
+// ScalePolicy decides how to scale a plan given the moving average of
+// pending provisions targeted at it. Operators can substitute their own
+// via Coordinator's Policy field to replace the default tier-bump
+// behavior entirely.
+type ScalePolicy interface {
+	Decide(pendingAvg float64, state PlanState) Decision
+}
+
+// ThresholdPolicy is the default ScalePolicy: once the moving average of
+// pending provisions for a plan exceeds Threshold, it bumps the plan's
+// tier up one step (or adds a shard if the tier is already at MaxTier),
+// capped by MaxTier/MaxShards.
+type ThresholdPolicy struct {
+	// Threshold is the pending-provisions moving average that triggers a
+	// scale-up.
+	Threshold float64
+}
+
+// Decide implements ScalePolicy.
+func (p ThresholdPolicy) Decide(pendingAvg float64, state PlanState) Decision {
+	if pendingAvg <= p.Threshold {
+		return Decision{Tier: state.Tier, Shards: state.Shards}
+	}
+
+	if next, ok := NextTier(state.Tier); ok && tierAtMost(next, state.MaxTier) {
+		return Decision{
+			Tier:   next,
+			Shards: state.Shards,
+			Scaled: true,
+			Reason: "pending provisions moving average exceeded threshold; bumped tier",
+		}
+	}
+
+	if state.MaxShards <= 0 || state.Shards < state.MaxShards {
+		return Decision{
+			Tier:   state.Tier,
+			Shards: state.Shards + 1,
+			Scaled: true,
+			Reason: "pending provisions moving average exceeded threshold; tier at MaxTier, added a shard",
+		}
+	}
+
+	return Decision{Tier: state.Tier, Shards: state.Shards}
+}
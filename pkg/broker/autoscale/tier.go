@@ -0,0 +1,57 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscale
+
+// tierLadder is Atlas's dedicated-cluster instance size ladder, smallest
+// first. Shared tiers (M0/M2/M5) are deliberately excluded: autoscaling a
+// shared-tier cluster isn't supported by Atlas, so a plan pinned to one
+// just won't match any entry here and NextTier will report no next step.
+var tierLadder = []string{
+	"M10", "M20", "M30", "M40", "M50", "M60",
+	"M80", "M100", "M140", "M200", "M300",
+	"M400", "M700",
+}
+
+// NextTier returns the tier one step above current, and whether there was
+// one. Unknown tiers report no next step rather than guessing.
+func NextTier(current string) (string, bool) {
+	for i, tier := range tierLadder {
+		if tier == current && i+1 < len(tierLadder) {
+			return tierLadder[i+1], true
+		}
+	}
+	return "", false
+}
+
+// tierIndex returns current's position in the ladder, or -1 if it's not on
+// it.
+func tierIndex(current string) int {
+	for i, tier := range tierLadder {
+		if tier == current {
+			return i
+		}
+	}
+	return -1
+}
+
+// tierAtMost reports whether candidate is no higher than max on the
+// ladder. An unrecognized max is treated as "no cap".
+func tierAtMost(candidate, max string) bool {
+	maxIdx := tierIndex(max)
+	if maxIdx < 0 {
+		return true
+	}
+	return tierIndex(candidate) <= maxIdx
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/autoscale"
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/dynamicplans"
+)
+
+const (
+	defaultAutoscaleThreshold      = 3.0
+	defaultAutoscaleSampleInterval = 30 * time.Second
+)
+
+func autoscaleThresholdFromEnv() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("ATLAS_BROKER_AUTOSCALE_THRESHOLD"), 64); err == nil {
+		return v
+	}
+	return defaultAutoscaleThreshold
+}
+
+func autoscaleDryRunFromEnv() bool {
+	dryRun, _ := strconv.ParseBool(os.Getenv("ATLAS_BROKER_AUTOSCALE_DRY_RUN"))
+	return dryRun
+}
+
+func autoscaleSampleIntervalFromEnv() time.Duration {
+	if v, err := time.ParseDuration(os.Getenv("ATLAS_BROKER_AUTOSCALE_SAMPLE_INTERVAL")); err == nil {
+		return v
+	}
+	return defaultAutoscaleSampleInterval
+}
+
+// autoscaleKey identifies a plan for autoscaling purposes: tier/shard
+// decisions are made per project+plan combination, since two instances of
+// the same plan in different projects shouldn't influence each other.
+func autoscaleKey(projectName, planID string) string {
+	return projectName + "/" + planID
+}
+
+// pendingProvisions tracks, per instanceID, the autoscaleKey it was marked
+// pending under, so TrackProvisionEnd can decrement exactly once no matter
+// how many times getPlan later resolves that same settled instance (every
+// Bind, Unbind and routine LastOperation poll against it would otherwise
+// re-trigger the decrement).
+type pendingProvisions struct {
+	mu  sync.Mutex
+	set map[string]string
+}
+
+func (p *pendingProvisions) start(instanceID, key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.set == nil {
+		p.set = make(map[string]string)
+	}
+	p.set[instanceID] = key
+}
+
+// end removes instanceID from the set and returns the key it was started
+// with and whether it was still pending (false if already settled, so the
+// caller knows not to decrement again).
+func (p *pendingProvisions) end(instanceID string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok := p.set[instanceID]
+	if ok {
+		delete(p.set, instanceID)
+	}
+	return key, ok
+}
+
+// TrackProvisionStart should be called when a new provision begins for
+// instanceID, so the autoscaler counts it as pending. getPlan calls this
+// the first time it resolves a brand-new instance's plan, which happens
+// once per provision regardless of which OSB operation (Provision, Bind,
+// LastOperation, ...) triggered the lookup.
+func (b *Broker) TrackProvisionStart(instanceID, projectName, planID string) {
+	b.pendingProvisions.start(instanceID, autoscaleKey(projectName, planID))
+	b.autoscaler.IncPending(autoscaleKey(projectName, planID))
+}
+
+// TrackProvisionEnd should be called once a provision for instanceID has
+// settled (succeeded or failed). getPlan calls this every time it finds the
+// instance's plan already persisted in the state backend, but the pending
+// counter is only decremented the first time: once instanceID is no longer
+// in the pending set, later calls are no-ops.
+func (b *Broker) TrackProvisionEnd(instanceID string) {
+	key, ok := b.pendingProvisions.end(instanceID)
+	if !ok {
+		return
+	}
+
+	b.autoscaler.DecPending(key)
+}
+
+// applyAutoscaling mutates dp's InstanceSizeName/NumShards in place if the
+// autoscaler decides the plan identified by planID is under sustained
+// pending-provision load, capped by the MaxTier/MaxNodes annotations on
+// the plan template's metadata.
+func (b *Broker) applyAutoscaling(dp *dynamicplans.Plan, planID string, metadata map[string]interface{}) {
+	if dp.Project == nil {
+		return
+	}
+
+	maxTier, _ := metadata["MaxTier"].(string)
+
+	var maxNodes int
+	if err := mapstructure.Decode(metadata["MaxNodes"], &maxNodes); err != nil {
+		maxNodes = 0
+	}
+
+	decision := b.autoscaler.Adjust(autoscaleKey(dp.Project.Name, planID), autoscale.PlanState{
+		Tier:      dp.InstanceSizeName,
+		MaxTier:   maxTier,
+		Shards:    dp.NumShards,
+		MaxShards: maxNodes,
+	})
+
+	dp.InstanceSizeName = decision.Tier
+	dp.NumShards = decision.Shards
+}
+
+// AutoscaleMetricsHandler serves atlas_osb_pending_provisions,
+// atlas_osb_scale_decisions_total and atlas_osb_current_tier in the
+// Prometheus exposition format. The caller is responsible for mounting it,
+// e.g. at /metrics.
+func (b *Broker) AutoscaleMetricsHandler() http.Handler {
+	return b.autoscaler.Handler()
+}
@@ -19,18 +19,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"os"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/Sectorbob/mlab-ns2/gae/ns/digest"
 	"github.com/goccy/go-yaml"
 	"github.com/gorilla/mux"
 	"github.com/mitchellh/mapstructure"
 	"github.com/mongodb/go-client-mongodb-atlas/mongodbatlas"
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/auth/oidc"
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/autoscale"
 	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/credentials"
 	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/dynamicplans"
 	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/statestorage"
+	statestorageinit "github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/statestorage/init"
 	"github.com/pivotal-cf/brokerapi/domain"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -39,17 +45,62 @@ import (
 // Ensure broker adheres to the ServiceBroker interface.
 var _ domain.ServiceBroker = new(Broker)
 
+// AuthMode selects how Broker.AuthMiddleware authenticates incoming
+// requests.
+type AuthMode string
+
+const (
+	// AuthModeBasic validates HTTP Basic credentials against
+	// credentials.Credentials.Broker, the broker's original behavior.
+	AuthModeBasic AuthMode = "basic"
+	// AuthModeOIDC validates an OIDC-issued bearer token.
+	AuthModeOIDC AuthMode = "oidc"
+	// AuthModeChain accepts either a valid bearer token or valid Basic
+	// credentials, so a platform can migrate identity providers without
+	// a flag day.
+	AuthModeChain AuthMode = "chain"
+)
+
 // Broker is responsible for translating OSB calls to Atlas API calls.
 // Implements the domain.ServiceBroker interface making it easy to spin up
 // an API server.
 type Broker struct {
-	logger      *zap.SugaredLogger
-	whitelist   Whitelist
-	credentials *credentials.Credentials
-	atlasURL    string
-	realmURL    string
-	catalog     *catalog
-	userAgent   string
+	logger            *zap.SugaredLogger
+	whitelist         Whitelist
+	credentials       *credentials.Credentials
+	atlasURL          string
+	realmURL          string
+	userAgent         string
+	stateBackendName  string
+	stateBackendConf  statestorage.Config
+	authMode          AuthMode
+	oidcAuth          *oidc.Authenticator
+	autoscaler        *autoscale.Coordinator
+	pendingProvisions pendingProvisions
+
+	// catalog is rebuilt on a timer by startCatalogRefreshLoop while
+	// request-handling goroutines read it concurrently, so every access
+	// goes through catalogMu rather than touching the field directly.
+	catalogMu sync.RWMutex
+	catalog   *catalog
+
+	catalogSubsMu sync.Mutex
+	catalogSubs   []catalogSubscriber
+}
+
+// currentCatalog returns the most recently built catalog.
+func (b *Broker) currentCatalog() *catalog {
+	b.catalogMu.RLock()
+	defer b.catalogMu.RUnlock()
+	return b.catalog
+}
+
+// rebuildCatalog calls buildCatalog under catalogMu, so it can safely run
+// concurrently with currentCatalog reads from in-flight requests.
+func (b *Broker) rebuildCatalog() {
+	b.catalogMu.Lock()
+	defer b.catalogMu.Unlock()
+	b.buildCatalog()
 }
 
 // New creates a new Broker with a logger.
@@ -61,19 +112,113 @@ func New(
 	whitelist Whitelist,
 	userAgent string,
 ) *Broker {
+	statestorageinit.Init()
+
 	b := &Broker{
-		logger:      logger,
-		credentials: credentials,
-		atlasURL:    atlasURL,
-		realmURL:    realmURL,
-		whitelist:   whitelist,
-		userAgent:   userAgent,
+		logger:           logger,
+		credentials:      credentials,
+		atlasURL:         atlasURL,
+		realmURL:         realmURL,
+		whitelist:        whitelist,
+		userAgent:        userAgent,
+		stateBackendName: stateBackendNameFromEnv(),
+		stateBackendConf: stateBackendConfFromEnv(logger),
+		authMode:         authModeFromCredentials(credentials),
 	}
 
-	b.buildCatalog()
+	if b.authMode != AuthModeBasic {
+		oidcAuth, err := newOIDCAuthenticator(logger, credentials)
+		if err != nil {
+			logger.Errorw("cannot set up OIDC authentication, falling back to basic auth", "err", err)
+			b.authMode = AuthModeBasic
+		} else {
+			b.oidcAuth = oidcAuth
+		}
+	}
+
+	b.autoscaler = autoscale.New(autoscale.ThresholdPolicy{Threshold: autoscaleThresholdFromEnv()}, 5, autoscaleDryRunFromEnv(), logger)
+	b.autoscaler.Start(autoscaleSampleIntervalFromEnv())
+
+	b.rebuildCatalog()
+	b.notifyCatalogSubscribers()
+	maybeStartK8sSync(b)
+	b.startCatalogRefreshLoop(catalogRefreshIntervalFromEnv())
+
 	return b
 }
 
+// authModeFromCredentials picks the AuthMode the broker was configured
+// with, defaulting to basic auth so deployments without an OIDC block keep
+// their current behavior. With an OIDC block, an empty or unrecognized
+// AuthMode selects chain.
+func authModeFromCredentials(creds *credentials.Credentials) AuthMode {
+	if creds == nil || creds.OIDC == nil {
+		return AuthModeBasic
+	}
+
+	switch AuthMode(creds.AuthMode) {
+	case AuthModeBasic:
+		return AuthModeBasic
+	case AuthModeOIDC, AuthModeChain:
+		return AuthMode(creds.AuthMode)
+	default:
+		return AuthModeChain
+	}
+}
+
+func newOIDCAuthenticator(logger *zap.SugaredLogger, creds *credentials.Credentials) (*oidc.Authenticator, error) {
+	if creds == nil || creds.OIDC == nil {
+		return nil, fmt.Errorf("no OIDC configuration provided")
+	}
+
+	return oidc.New(context.Background(), logger, oidc.Config{
+		IssuerURL:           creds.OIDC.IssuerURL,
+		Audience:            creds.OIDC.Audience,
+		RequiredScopes:      creds.OIDC.RequiredScopes,
+		JWKSRefreshInterval: creds.OIDC.JWKSRefreshInterval,
+	})
+}
+
+// stateBackendNameFromEnv reads ATLAS_BROKER_STATE_BACKEND, defaulting to
+// "realm" so existing deployments keep their current behavior unless they
+// opt into a different backend.
+func stateBackendNameFromEnv() string {
+	if name := os.Getenv("ATLAS_BROKER_STATE_BACKEND"); name != "" {
+		return name
+	}
+	return "realm"
+}
+
+// stateBackendConfFromEnv loads the configuration blob for the chosen state
+// backend, preferring the inline JSON in ATLAS_BROKER_STATE_BACKEND_CONFIG
+// and falling back to the file named by
+// ATLAS_BROKER_STATE_BACKEND_CONFIG_FILE. Backends that need no
+// configuration (e.g. "inmem") simply ignore the empty Raw map.
+func stateBackendConfFromEnv(logger *zap.SugaredLogger) statestorage.Config {
+	raw := os.Getenv("ATLAS_BROKER_STATE_BACKEND_CONFIG")
+	if raw == "" {
+		path := os.Getenv("ATLAS_BROKER_STATE_BACKEND_CONFIG_FILE")
+		if path == "" {
+			return statestorage.Config{}
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			logger.Errorw("cannot read state backend config file, using empty config", "path", path, "err", err)
+			return statestorage.Config{}
+		}
+		raw = string(b)
+	}
+
+	var cfg map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		logger.Errorw("cannot parse state backend config, using empty config", "err", err)
+		return statestorage.Config{}
+	}
+
+	return statestorage.Config{Raw: cfg}
+}
+
 func (b *Broker) funcLogger() *zap.SugaredLogger {
 	pc := []uintptr{0}
 	runtime.Callers(2, pc)
@@ -85,7 +230,7 @@ func (b *Broker) funcLogger() *zap.SugaredLogger {
 
 func (b *Broker) parsePlan(ctx dynamicplans.Context, planID string) (dp *dynamicplans.Plan, err error) {
 	logger := b.funcLogger()
-	sp, ok := b.catalog.plans[planID]
+	sp, ok := b.currentCatalog().plans[planID]
 	if !ok {
 		err = fmt.Errorf("plan ID %q not found in catalog", planID)
 		return
@@ -120,6 +265,8 @@ func (b *Broker) parsePlan(ctx dynamicplans.Context, planID string) (dp *dynamic
 		logger.Infow("Merged final plan instance:", "plan", dp.SafeCopy())
 	}
 
+	b.applyAutoscaling(dp, planID, sp.Metadata.AdditionalMetadata)
+
 	return dp, nil
 }
 
@@ -153,6 +300,12 @@ func (b *Broker) getPlan(ctx context.Context, instanceID string, planID string,
 	// existing instance: try to get from state store
 	dp, err = b.getInstancePlan(ctx, instanceID)
 	if err == nil {
+		// The instance's plan is persisted once its provision has
+		// settled, so every call that reaches it (bind, deprovision,
+		// later LastOperation polls) is a safe place to mark the
+		// provision no longer pending; TrackProvisionEnd only acts on
+		// the first call for a given instanceID.
+		b.TrackProvisionEnd(instanceID)
 		return
 	}
 
@@ -173,6 +326,8 @@ func (b *Broker) getPlan(ctx context.Context, instanceID string, planID string,
 		return
 	}
 
+	b.TrackProvisionStart(instanceID, dp.Project.Name, planID)
+
 	return
 }
 
@@ -224,16 +379,81 @@ func (b *Broker) getClient(ctx context.Context, instanceID string, planID string
 	return
 }
 
-func (b *Broker) getState(orgID string) (*statestorage.RealmStateStorage, error) {
-	key, err := b.credentials.ByOrg(orgID)
-	if err != nil {
-		return nil, err
+// getState resolves the configured StateBackend for orgID. It now returns
+// the StateBackend interface rather than the concrete *RealmStateStorage it
+// used to; code that still constructs a *RealmStateStorage through
+// statestorage.Get directly (pre-registry) can wrap that value with
+// statestorage.NewDeprecatedShim to get a StateBackend.
+func (b *Broker) getState(orgID string) (statestorage.StateBackend, error) {
+	factory := statestorageinit.Backend(b.stateBackendName)
+	if factory == nil {
+		return nil, fmt.Errorf("no state storage backend registered under name %q", b.stateBackendName)
+	}
+
+	backend := factory()
+
+	cfg := b.stateBackendConf
+	if b.stateBackendName == "realm" {
+		key, err := b.credentials.ByOrg(orgID)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg = mergeStateBackendConfig(cfg, map[string]interface{}{
+			"publicKey":  key.PublicKey,
+			"privateKey": key.PrivateKey,
+			"realmURL":   b.realmURL,
+		})
+	}
+
+	configurable, ok := backend.(statestorage.Configurable)
+	if ok {
+		if err := configurable.Configure(cfg); err != nil {
+			return nil, errors.Wrapf(err, "cannot configure %q state backend", b.stateBackendName)
+		}
 	}
 
-	return statestorage.Get(key, b.atlasURL, b.realmURL, b.logger)
+	return backend, nil
 }
 
+// mergeStateBackendConfig overlays extra on top of base without mutating
+// base, so per-call values (like an org's Realm credentials) don't leak
+// into the Broker's shared configuration.
+func mergeStateBackendConfig(base statestorage.Config, extra map[string]interface{}) statestorage.Config {
+	merged := make(map[string]interface{}, len(base.Raw)+len(extra))
+	for k, v := range base.Raw {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return statestorage.Config{Raw: merged}
+}
+
+// AuthMiddleware returns the mux.MiddlewareFunc that guards every broker
+// endpoint, chosen by b.authMode.
 func (b *Broker) AuthMiddleware() mux.MiddlewareFunc {
+	basic := b.basicAuthMiddleware()
+
+	switch b.authMode {
+	case AuthModeOIDC:
+		if b.oidcAuth != nil {
+			return b.oidcAuth.Middleware()
+		}
+		return basic
+
+	case AuthModeChain:
+		if b.oidcAuth == nil {
+			return basic
+		}
+		return chainMiddleware(b.oidcAuth.Middleware(), basic)
+
+	default:
+		return basic
+	}
+}
+
+func (b *Broker) basicAuthMiddleware() mux.MiddlewareFunc {
 	if b.credentials != nil {
 		return authMiddleware(*b.credentials.Broker)
 	}
@@ -241,6 +461,25 @@ func (b *Broker) AuthMiddleware() mux.MiddlewareFunc {
 	return simpleAuthMiddleware(b.atlasURL)
 }
 
+// chainMiddleware sends a request carrying an "Authorization: Bearer ..."
+// header to first and every other request to second; a request rejected by
+// first is not retried against second. This lets a deployment accept either
+// a bearer token or Basic credentials during a migration between the two.
+func chainMiddleware(first, second mux.MiddlewareFunc) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		firstHandler := first(next)
+		secondHandler := second(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Header["Authorization"]; ok && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				firstHandler.ServeHTTP(w, r)
+				return
+			}
+			secondHandler.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (b *Broker) GetDashboardURL(groupID, clusterName string) string {
 	apiURL, err := url.Parse(b.atlasURL)
 	if err != nil {
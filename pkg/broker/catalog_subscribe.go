@@ -0,0 +1,85 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"os"
+	"time"
+)
+
+const defaultCatalogRefreshInterval = 5 * time.Minute
+
+// catalogSubscriber is notified every time the broker builds a new
+// catalog, so other subsystems (e.g. k8sproxy) can react without polling
+// Broker.catalog themselves.
+type catalogSubscriber func(*catalog)
+
+// Subscribe registers fn to be called whenever buildCatalog produces a new
+// catalog, and immediately once with the current one if buildCatalog has
+// already run.
+func (b *Broker) Subscribe(fn catalogSubscriber) {
+	b.catalogSubsMu.Lock()
+	b.catalogSubs = append(b.catalogSubs, fn)
+	b.catalogSubsMu.Unlock()
+
+	if current := b.currentCatalog(); current != nil {
+		fn(current)
+	}
+}
+
+// notifyCatalogSubscribers is called after (re)building the catalog.
+func (b *Broker) notifyCatalogSubscribers() {
+	b.catalogSubsMu.Lock()
+	subs := make([]catalogSubscriber, len(b.catalogSubs))
+	copy(subs, b.catalogSubs)
+	b.catalogSubsMu.Unlock()
+
+	current := b.currentCatalog()
+	for _, fn := range subs {
+		fn(current)
+	}
+}
+
+// catalogRefreshIntervalFromEnv reads ATLAS_BROKER_CATALOG_REFRESH_INTERVAL,
+// defaulting to defaultCatalogRefreshInterval. A value of 0 disables the
+// periodic refresh.
+func catalogRefreshIntervalFromEnv() time.Duration {
+	if v := os.Getenv("ATLAS_BROKER_CATALOG_REFRESH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return defaultCatalogRefreshInterval
+}
+
+// startCatalogRefreshLoop periodically rebuilds the catalog and notifies
+// subscribers (e.g. the k8ssync reconciler), so they see Atlas-side catalog
+// changes without requiring a broker restart. It's a no-op when interval is
+// not positive.
+func (b *Broker) startCatalogRefreshLoop(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			b.rebuildCatalog()
+			b.notifyCatalogSubscribers()
+		}
+	}()
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package credentials holds the Atlas API keys (and, for deployments that
+// opt into it, the OIDC configuration) the broker authenticates with.
+package credentials
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIKey is an Atlas programmatic API key pair, optionally scoped to a
+// specific Atlas org.
+type APIKey struct {
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+	OrgID      string `json:"orgId,omitempty"`
+}
+
+// OIDCConfig configures the broker's OIDC bearer-token authenticator. It is
+// consulted unless Credentials.AuthMode is "basic"; an empty or unrecognized
+// AuthMode is treated as "chain".
+type OIDCConfig struct {
+	// IssuerURL is the OIDC issuer; its .well-known/openid-configuration
+	// document is used to discover the JWKS endpoint.
+	IssuerURL string `json:"issuerUrl"`
+
+	// Audience is the expected "aud" claim, typically the broker's OAuth
+	// client ID.
+	Audience string `json:"audience"`
+
+	// RequiredScopes, if non-empty, must all be present in the token's
+	// "scope" claim.
+	RequiredScopes []string `json:"requiredScopes"`
+
+	// JWKSRefreshInterval controls how often the signing keys are
+	// re-fetched from the issuer. Defaults to one hour when zero.
+	JWKSRefreshInterval time.Duration `json:"jwksRefreshInterval"`
+}
+
+// Credentials holds the Atlas API keys the broker uses: one for broker-level
+// (OSB platform) Basic auth, and one per Atlas org for calls made on behalf
+// of that org's instances.
+type Credentials struct {
+	// Broker authenticates inbound OSB requests under AuthModeBasic.
+	Broker *APIKey `json:"broker"`
+
+	// OrgsByID maps an Atlas org ID to the API key used for Atlas calls
+	// scoped to that org.
+	OrgsByID map[string]APIKey `json:"orgs"`
+
+	// AuthMode selects how inbound OSB requests are authenticated; see
+	// broker.AuthMode. Ignored unless OIDC is also set.
+	AuthMode string `json:"authMode"`
+
+	// OIDC configures bearer-token authentication. A nil value means the
+	// broker falls back to Basic auth regardless of AuthMode.
+	OIDC *OIDCConfig `json:"oidc"`
+}
+
+// ByOrg returns the API key registered for orgID.
+func (c *Credentials) ByOrg(orgID string) (APIKey, error) {
+	key, ok := c.OrgsByID[orgID]
+	if !ok {
+		return APIKey{}, fmt.Errorf("no credentials configured for org %q", orgID)
+	}
+	return key, nil
+}
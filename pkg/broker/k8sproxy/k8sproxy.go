@@ -0,0 +1,208 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package k8sproxy keeps a Kubernetes cluster's ClusterServiceBroker object
+// (and the Secret its credentials live in) in sync with the catalog a
+// running atlas-osb broker exposes, the way
+// Peripli/service-broker-proxy-k8s keeps service-catalog in sync with an
+// arbitrary OSB-compliant broker.
+//
+// It deliberately does not create ServiceClass/ServicePlan objects itself:
+// the in-cluster service-catalog controller fetches those from the
+// broker's /v2/catalog over HTTP once it sees the ClusterServiceBroker and
+// honors RelistRequests. Reconciler's job is just to keep that object (and
+// its credentials Secret) pointed at the right place and to bump
+// RelistRequests when the catalog changes.
+package k8sproxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pivotal-cf/brokerapi/domain"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterServiceBrokerGVR identifies the service-catalog CRD this package
+// reconciles. There's no generated typed client for it vendored here, so
+// it's addressed through the dynamic client like any other CRD.
+var clusterServiceBrokerGVR = schema.GroupVersionResource{
+	Group:    "servicecatalog.k8s.io",
+	Version:  "v1beta1",
+	Resource: "clusterservicebrokers",
+}
+
+// Credentials are the Basic-auth credentials service-catalog should present
+// to the broker, stored in a Secret the ClusterServiceBroker references.
+// Bearer-token auth is not configured: the ClusterServiceBroker only ever
+// gets a basic authInfo.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Config configures a Reconciler.
+type Config struct {
+	// BrokerName is the name of the ClusterServiceBroker object to
+	// reconcile.
+	BrokerName string
+	// BrokerURL is the broker's externally reachable URL.
+	BrokerURL string
+	// Namespace is where the credentials Secret is created.
+	Namespace string
+	// SecretName is the name of the credentials Secret.
+	SecretName string
+	// Credentials are written into the Secret. Zero value means the
+	// ClusterServiceBroker is configured with no AuthInfo (e.g. the
+	// broker is reachable without auth, or uses mutual TLS handled
+	// elsewhere).
+	Credentials Credentials
+}
+
+// Reconciler reconciles a single ClusterServiceBroker + Secret pair against
+// the catalog it's given via Reconcile.
+type Reconciler struct {
+	cfg    Config
+	dyn    dynamic.Interface
+	client kubernetes.Interface
+
+	lastSyncedHash string
+}
+
+// New returns a Reconciler that talks to the cluster through dyn (for the
+// ClusterServiceBroker CRD) and client (for the credentials Secret).
+func New(cfg Config, client kubernetes.Interface, dyn dynamic.Interface) *Reconciler {
+	return &Reconciler{cfg: cfg, client: client, dyn: dyn}
+}
+
+// Reconcile creates or updates the ClusterServiceBroker and its credentials
+// Secret so they reflect cfg, and bumps RelistRequests if services differs
+// from the last catalog Reconcile saw.
+func (r *Reconciler) Reconcile(ctx context.Context, services []domain.Service) error {
+	if err := r.reconcileSecret(ctx); err != nil {
+		return errors.Wrap(err, "cannot reconcile credentials secret")
+	}
+
+	changed, err := r.catalogChanged(services)
+	if err != nil {
+		return errors.Wrap(err, "cannot hash catalog")
+	}
+
+	if err := r.reconcileClusterServiceBroker(ctx, changed); err != nil {
+		return errors.Wrap(err, "cannot reconcile ClusterServiceBroker")
+	}
+
+	return nil
+}
+
+func (r *Reconciler) catalogChanged(services []domain.Service) (bool, error) {
+	raw, err := json.Marshal(services)
+	if err != nil {
+		return false, err
+	}
+
+	sum := fmt.Sprintf("%x", sha256.Sum256(raw))
+	changed := sum != r.lastSyncedHash
+	r.lastSyncedHash = sum
+	return changed, nil
+}
+
+func (r *Reconciler) reconcileSecret(ctx context.Context) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.cfg.SecretName,
+			Namespace: r.cfg.Namespace,
+		},
+		Data: map[string][]byte{
+			"username": []byte(r.cfg.Credentials.Username),
+			"password": []byte(r.cfg.Credentials.Password),
+		},
+	}
+
+	secrets := r.client.CoreV1().Secrets(r.cfg.Namespace)
+
+	_, err := secrets.Get(ctx, r.cfg.SecretName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	default:
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	}
+}
+
+func (r *Reconciler) reconcileClusterServiceBroker(ctx context.Context, bumpRelist bool) error {
+	brokers := r.dyn.Resource(clusterServiceBrokerGVR)
+
+	existing, err := brokers.Get(ctx, r.cfg.BrokerName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		obj := r.newClusterServiceBroker(1)
+		_, err = brokers.Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	relistRequests, _, _ := unstructured.NestedInt64(existing.Object, "spec", "relistRequests")
+	if bumpRelist {
+		relistRequests++
+	}
+
+	obj := r.newClusterServiceBroker(relistRequests)
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	_, err = brokers.Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+func (r *Reconciler) newClusterServiceBroker(relistRequests int64) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"url":            r.cfg.BrokerURL,
+		"relistRequests": relistRequests,
+	}
+
+	if r.cfg.Credentials != (Credentials{}) {
+		spec["authInfo"] = map[string]interface{}{
+			"basic": map[string]interface{}{
+				"secretRef": map[string]interface{}{
+					"namespace": r.cfg.Namespace,
+					"name":      r.cfg.SecretName,
+				},
+			},
+		}
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "servicecatalog.k8s.io/v1beta1",
+			"kind":       "ClusterServiceBroker",
+			"metadata": map[string]interface{}{
+				"name": r.cfg.BrokerName,
+			},
+			"spec": spec,
+		},
+	}
+}
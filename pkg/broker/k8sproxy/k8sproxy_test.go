@@ -0,0 +1,118 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sproxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi/domain"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestReconciler() (*Reconciler, *dynamicfake.FakeDynamicClient, *fake.Clientset) {
+	scheme := runtime.NewScheme()
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		clusterServiceBrokerGVR: "ClusterServiceBrokerList",
+	})
+	kubeClient := fake.NewSimpleClientset()
+
+	cfg := Config{
+		BrokerName: "atlas-osb",
+		BrokerURL:  "https://atlas-osb.example.com",
+		Namespace:  "atlas-osb",
+		SecretName: "atlas-osb-creds",
+		Credentials: Credentials{
+			Username: "broker",
+			Password: "s3cret",
+		},
+	}
+
+	return New(cfg, kubeClient, dynClient), dynClient, kubeClient
+}
+
+func getBroker(t *testing.T, dynClient *dynamicfake.FakeDynamicClient, name string) *unstructured.Unstructured {
+	t.Helper()
+
+	obj, err := dynClient.Resource(clusterServiceBrokerGVR).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("cannot get ClusterServiceBroker %q: %v", name, err)
+	}
+	return obj
+}
+
+func TestReconcile_createsBrokerAndSecret(t *testing.T) {
+	r, dynClient, kubeClient := newTestReconciler()
+
+	services := []domain.Service{{ID: "svc-1", Name: "atlas"}}
+	if err := r.Reconcile(context.Background(), services); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	obj := getBroker(t, dynClient, "atlas-osb")
+	url, _, _ := unstructured.NestedString(obj.Object, "spec", "url")
+	if url != "https://atlas-osb.example.com" {
+		t.Errorf("spec.url = %q, want broker URL", url)
+	}
+
+	relist, _, _ := unstructured.NestedInt64(obj.Object, "spec", "relistRequests")
+	if relist != 1 {
+		t.Errorf("spec.relistRequests = %d, want 1 on first sync", relist)
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets("atlas-osb").Get(context.Background(), "atlas-osb-creds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("cannot get credentials secret: %v", err)
+	}
+	if string(secret.Data["username"]) != "broker" {
+		t.Errorf("secret username = %q, want %q", secret.Data["username"], "broker")
+	}
+}
+
+func TestReconcile_bumpsRelistOnlyWhenCatalogChanges(t *testing.T) {
+	r, dynClient, _ := newTestReconciler()
+	ctx := context.Background()
+
+	services := []domain.Service{{ID: "svc-1", Name: "atlas"}}
+	if err := r.Reconcile(ctx, services); err != nil {
+		t.Fatalf("Reconcile() #1 error = %v", err)
+	}
+
+	// Same catalog again: relistRequests should not move.
+	if err := r.Reconcile(ctx, services); err != nil {
+		t.Fatalf("Reconcile() #2 error = %v", err)
+	}
+	obj := getBroker(t, dynClient, "atlas-osb")
+	relist, _, _ := unstructured.NestedInt64(obj.Object, "spec", "relistRequests")
+	if relist != 1 {
+		t.Errorf("spec.relistRequests = %d after unchanged catalog, want 1", relist)
+	}
+
+	// New plan appears: relistRequests should bump.
+	changedServices := []domain.Service{{ID: "svc-1", Name: "atlas"}, {ID: "svc-2", Name: "atlas-sharded"}}
+	if err := r.Reconcile(ctx, changedServices); err != nil {
+		t.Fatalf("Reconcile() #3 error = %v", err)
+	}
+	obj = getBroker(t, dynClient, "atlas-osb")
+	relist, _, _ = unstructured.NestedInt64(obj.Object, "spec", "relistRequests")
+	if relist != 2 {
+		t.Errorf("spec.relistRequests = %d after catalog changed, want 2", relist)
+	}
+}
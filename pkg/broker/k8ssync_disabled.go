@@ -0,0 +1,24 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !k8ssync
+// +build !k8ssync
+
+package broker
+
+// maybeStartK8sSync is a no-op in the default build, which doesn't link
+// k8sproxy (and therefore client-go) at all. Build with `-tags k8ssync` to
+// get in-process syncing, or run cmd/atlas-osb-k8s-proxy as a separate
+// process against this broker.
+func maybeStartK8sSync(b *Broker) {}
@@ -0,0 +1,100 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build k8ssync
+// +build k8ssync
+
+package broker
+
+import (
+	"context"
+	"os"
+
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/k8sproxy"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// maybeStartK8sSync starts the k8sproxy reconcile loop as a background
+// goroutine when K8S_SYNC_ENABLED is set. It's only compiled into binaries
+// built with `-tags k8ssync`; the default atlas-osb binary doesn't pay for
+// the client-go dependency at all. Deployments that don't want client-go
+// linked into their broker process at all should run the standalone
+// cmd/atlas-osb-k8s-proxy binary against this broker's HTTP catalog
+// instead.
+func maybeStartK8sSync(b *Broker) {
+	if os.Getenv("K8S_SYNC_ENABLED") != "true" {
+		return
+	}
+
+	brokerURL := os.Getenv("K8S_SYNC_BROKER_URL")
+	if brokerURL == "" {
+		b.logger.Error("k8s-sync enabled but K8S_SYNC_BROKER_URL is not set (this broker's own externally reachable URL, not the Atlas API URL), skipping")
+		return
+	}
+
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		b.logger.Errorw("k8s-sync enabled but not running in-cluster, skipping", "err", err)
+		return
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		b.logger.Errorw("cannot create Kubernetes client for k8s-sync", "err", err)
+		return
+	}
+
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		b.logger.Errorw("cannot create Kubernetes dynamic client for k8s-sync", "err", err)
+		return
+	}
+
+	var creds k8sproxy.Credentials
+	if b.credentials != nil && b.credentials.Broker != nil {
+		creds = k8sproxy.Credentials{
+			Username: b.credentials.Broker.PublicKey,
+			Password: b.credentials.Broker.PrivateKey,
+		}
+	}
+
+	reconciler := k8sproxy.New(k8sproxy.Config{
+		BrokerName:  envOrDefault("K8S_SYNC_BROKER_NAME", "atlas-osb"),
+		BrokerURL:   brokerURL,
+		Namespace:   envOrDefault("K8S_SYNC_NAMESPACE", "default"),
+		SecretName:  envOrDefault("K8S_SYNC_SECRET_NAME", "atlas-osb-credentials"),
+		Credentials: creds,
+	}, client, dyn)
+
+	b.Subscribe(func(c *catalog) {
+		services, err := b.Services(context.Background())
+		if err != nil {
+			b.logger.Errorw("cannot build catalog for k8s-sync", "err", err)
+			return
+		}
+
+		if err := reconciler.Reconcile(context.Background(), services); err != nil {
+			b.logger.Errorw("k8s-sync reconcile failed", "err", err)
+		}
+	})
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statestorage
+
+// StateBackend is implemented by anything that can durably hold instance and
+// binding metadata keyed by instance ID. It is the seam that lets the broker
+// swap its persistence layer (Realm, an in-memory map, S3, ...) without the
+// call sites in pkg/broker caring which one is in use.
+type StateBackend interface {
+	// Put stores value under instanceID, replacing any existing value.
+	Put(instanceID string, value interface{}) error
+
+	// Get loads the value stored under instanceID into out. It returns
+	// ErrNotFound if no value has been stored for instanceID.
+	Get(instanceID string, out interface{}) error
+
+	// Delete removes the value stored under instanceID, if any.
+	Delete(instanceID string) error
+
+	// List returns the instance IDs currently known to the backend.
+	List() ([]string, error)
+}
+
+// Configurable is implemented by every built-in backend so the registry can
+// apply the broker's configuration blob to a freshly constructed,
+// zero-value backend before it's used.
+type Configurable interface {
+	Configure(Config) error
+}
+
+// Locker is optionally implemented by backends that can coordinate
+// concurrent access to the same instanceID across broker replicas.
+// Backends that don't need locking (e.g. a single-process in-memory store)
+// may leave it unimplemented.
+type Locker interface {
+	Lock(instanceID string) error
+	Unlock(instanceID string) error
+}
+
+// ErrNotFound is returned by StateBackend.Get when instanceID has no
+// associated value.
+type ErrNotFound string
+
+func (e ErrNotFound) Error() string {
+	return "no state found for instance " + string(e)
+}
+
+// Config is the backend-agnostic configuration blob threaded through from
+// Broker. Each backend picks the keys it cares about out of Raw and ignores
+// the rest, mirroring how Terraform backends read their own subset of the
+// block passed to Configure.
+type Config struct {
+	// Raw holds the backend configuration as decoded from env vars or a
+	// config file, e.g. {"bucket": "...", "region": "..."} for the s3
+	// backend.
+	Raw map[string]interface{}
+}
+
+func (c Config) string(key string) string {
+	if c.Raw == nil {
+		return ""
+	}
+	s, _ := c.Raw[key].(string)
+	return s
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statestorage
+
+import (
+	"encoding/json"
+	"strings"
+
+	consul "github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+)
+
+// ConsulStateStorage stores one KV entry per instance under a configurable
+// path prefix, and uses Consul sessions to implement Locker so multiple
+// broker replicas don't race on the same instance.
+type ConsulStateStorage struct {
+	kv     *consul.KV
+	prefix string
+}
+
+func NewConsulBackend() StateBackend {
+	return &ConsulStateStorage{}
+}
+
+// Configure reads "address", "token" and "prefix" out of cfg.
+func (s *ConsulStateStorage) Configure(cfg Config) error {
+	conf := consul.DefaultConfig()
+	if addr := cfg.string("address"); addr != "" {
+		conf.Address = addr
+	}
+	if token := cfg.string("token"); token != "" {
+		conf.Token = token
+	}
+
+	client, err := consul.NewClient(conf)
+	if err != nil {
+		return errors.Wrap(err, "cannot create Consul client")
+	}
+
+	s.kv = client.KV()
+	s.prefix = cfg.string("prefix")
+	return nil
+}
+
+func (s *ConsulStateStorage) key(instanceID string) string {
+	return s.prefix + instanceID
+}
+
+// Put implements StateBackend.
+func (s *ConsulStateStorage) Put(instanceID string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.kv.Put(&consul.KVPair{Key: s.key(instanceID), Value: raw}, nil)
+	return errors.Wrap(err, "cannot put Consul KV entry")
+}
+
+// Get implements StateBackend.
+func (s *ConsulStateStorage) Get(instanceID string, out interface{}) error {
+	pair, _, err := s.kv.Get(s.key(instanceID), nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot get Consul KV entry")
+	}
+	if pair == nil {
+		return ErrNotFound(instanceID)
+	}
+
+	return json.Unmarshal(pair.Value, out)
+}
+
+// Delete implements StateBackend.
+func (s *ConsulStateStorage) Delete(instanceID string) error {
+	_, err := s.kv.Delete(s.key(instanceID), nil)
+	return errors.Wrap(err, "cannot delete Consul KV entry")
+}
+
+// List implements StateBackend.
+func (s *ConsulStateStorage) List() ([]string, error) {
+	pairs, _, err := s.kv.List(s.prefix, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list Consul KV entries")
+	}
+
+	ids := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		id := strings.TrimPrefix(p.Key, s.prefix)
+		// Lock keeps its lock entries under the same prefix; skip them.
+		if strings.HasPrefix(id, "locks/") {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Lock implements Locker using a Consul session-backed KV lock.
+func (s *ConsulStateStorage) Lock(instanceID string) error {
+	sessionID, _, err := s.kv.Client().Session().Create(&consul.SessionEntry{
+		Name:      "atlas-osb-lock-" + instanceID,
+		TTL:       "30s",
+		LockDelay: 0,
+	}, nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot create Consul session")
+	}
+
+	acquired, _, err := s.kv.Acquire(&consul.KVPair{
+		Key:     s.prefix + "locks/" + instanceID,
+		Value:   []byte(sessionID),
+		Session: sessionID,
+	}, nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot acquire Consul lock")
+	}
+	if !acquired {
+		return errors.Errorf("instance %q is already locked", instanceID)
+	}
+	return nil
+}
+
+// Unlock implements Locker.
+func (s *ConsulStateStorage) Unlock(instanceID string) error {
+	_, _, err := s.kv.Release(&consul.KVPair{Key: s.prefix + "locks/" + instanceID}, nil)
+	return errors.Wrap(err, "cannot release Consul lock")
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statestorage
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStateStorage stores one object per instance under a configurable
+// prefix in a single GCS bucket.
+type GCSStateStorage struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+func NewGCSBackend() StateBackend {
+	return &GCSStateStorage{}
+}
+
+// Configure reads "bucket" and "prefix" out of cfg.
+func (s *GCSStateStorage) Configure(cfg Config) error {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "cannot create GCS client")
+	}
+
+	s.bucket = client.Bucket(cfg.string("bucket"))
+	s.prefix = cfg.string("prefix")
+	return nil
+}
+
+func (s *GCSStateStorage) object(instanceID string) *storage.ObjectHandle {
+	return s.bucket.Object(s.prefix + instanceID + ".json")
+}
+
+// Put implements StateBackend.
+func (s *GCSStateStorage) Put(instanceID string, value interface{}) error {
+	ctx := context.Background()
+	w := s.object(instanceID).NewWriter(ctx)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return errors.Wrap(w.Close(), "cannot write GCS object")
+}
+
+// Get implements StateBackend.
+func (s *GCSStateStorage) Get(instanceID string, out interface{}) error {
+	ctx := context.Background()
+	r, err := s.object(instanceID).NewReader(ctx)
+	if err != nil {
+		return ErrNotFound(instanceID)
+	}
+	defer r.Close()
+
+	return json.NewDecoder(r).Decode(out)
+}
+
+// Delete implements StateBackend.
+func (s *GCSStateStorage) Delete(instanceID string) error {
+	err := s.object(instanceID).Delete(context.Background())
+	return errors.Wrap(err, "cannot delete GCS object")
+}
+
+// List implements StateBackend.
+func (s *GCSStateStorage) List() ([]string, error) {
+	ctx := context.Background()
+	it := s.bucket.Objects(ctx, &storage.Query{Prefix: s.prefix})
+
+	var ids []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot list GCS objects")
+		}
+
+		name := strings.TrimPrefix(attrs.Name, s.prefix)
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	return ids, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package init registers the built-in statestorage.StateBackend
+// implementations, following the same shape as Terraform's
+// backend/init package: a lazily-populated name -> factory map that
+// out-of-tree code can also register into via Set.
+package init
+
+import (
+	"sync"
+
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/statestorage"
+)
+
+var (
+	backends     map[string]func() statestorage.StateBackend
+	backendsLock sync.Mutex
+	backendsInit bool
+)
+
+// Init populates the backend registry with the built-in backends, filling
+// in only the names that aren't already registered. services is reserved
+// for backends that need to share a discovery/service-catalog client the
+// way Terraform's cloud backends do; none of the built-ins use it today,
+// but callers are expected to pass whatever they have so adding one later
+// doesn't change this signature.
+//
+// Init must not overwrite existing entries: out-of-tree backends register
+// themselves under Set from their own package init() funcs, which run
+// before main() (and so before Broker.New() calls Init). Stomping the map
+// here would silently erase those registrations the moment a broker is
+// constructed.
+func Init(services ...interface{}) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	if backends == nil {
+		backends = make(map[string]func() statestorage.StateBackend)
+	}
+
+	for name, factory := range map[string]func() statestorage.StateBackend{
+		"realm":    statestorage.NewRealmBackend,
+		"inmem":    statestorage.NewInmemBackend,
+		"s3":       statestorage.NewS3Backend,
+		"gcs":      statestorage.NewGCSBackend,
+		"consul":   statestorage.NewConsulBackend,
+		"postgres": statestorage.NewPostgresBackend,
+	} {
+		if _, exists := backends[name]; !exists {
+			backends[name] = factory
+		}
+	}
+	backendsInit = true
+}
+
+// Backend returns the factory registered under name, or nil if there isn't
+// one. Init must have been called first; Backend panics otherwise so
+// misconfigured callers fail fast instead of silently getting a nil
+// backend.
+func Backend(name string) func() statestorage.StateBackend {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	if !backendsInit {
+		panic("statestorage/init: Backend called before Init")
+	}
+	return backends[name]
+}
+
+// Set registers factory under name, overwriting any existing entry. Passing
+// a nil factory removes name from the registry. This is the extension point
+// for out-of-tree backends: they call Set from an init() func in their own
+// package, after importing this package for its side effect of being
+// initialized.
+func Set(name string, factory func() statestorage.StateBackend) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	if backends == nil {
+		backends = make(map[string]func() statestorage.StateBackend)
+	}
+
+	if factory == nil {
+		delete(backends, name)
+		return
+	}
+	backends[name] = factory
+}
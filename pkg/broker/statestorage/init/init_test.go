@@ -0,0 +1,91 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package init
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/statestorage"
+)
+
+func TestInit_backend(t *testing.T) {
+	Init()
+
+	tests := []struct {
+		name string
+		want interface{}
+	}{
+		{"realm", &statestorage.RealmStateStorage{}},
+		{"inmem", &statestorage.InmemStateStorage{}},
+		{"s3", &statestorage.S3StateStorage{}},
+		{"gcs", &statestorage.GCSStateStorage{}},
+		{"consul", &statestorage.ConsulStateStorage{}},
+		{"postgres", &statestorage.PostgresStateStorage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Backend(tt.name)
+			if f == nil {
+				t.Fatalf("Backend(%q) = nil, want a factory", tt.name)
+			}
+
+			got := f()
+			wantType := fmt.Sprintf("%T", tt.want)
+			gotType := fmt.Sprintf("%T", got)
+			if gotType != wantType {
+				t.Errorf("Backend(%q)() = %s, want %s", tt.name, gotType, wantType)
+			}
+		})
+	}
+}
+
+func TestInit_unknownBackend(t *testing.T) {
+	Init()
+
+	if f := Backend("does-not-exist"); f != nil {
+		t.Errorf("Backend(%q) = %v, want nil", "does-not-exist", f)
+	}
+}
+
+func TestSet_registersAndRemoves(t *testing.T) {
+	Init()
+
+	Set("custom", statestorage.NewInmemBackend)
+	if f := Backend("custom"); f == nil {
+		t.Fatal("Backend(\"custom\") = nil after Set, want a factory")
+	}
+
+	Set("custom", nil)
+	if f := Backend("custom"); f != nil {
+		t.Errorf("Backend(\"custom\") = %v after Set(nil), want nil", f)
+	}
+}
+
+// TestInit_doesNotStompExistingRegistrations guards against the extension
+// point this package exists for: an out-of-tree backend that calls Set from
+// its own package init() (which runs before Broker.New calls Init here)
+// must still be registered afterwards.
+func TestInit_doesNotStompExistingRegistrations(t *testing.T) {
+	Set("custom", statestorage.NewInmemBackend)
+	defer Set("custom", nil)
+
+	Init()
+
+	if f := Backend("custom"); f == nil {
+		t.Error("Backend(\"custom\") = nil after Init, want Set's registration to survive")
+	}
+}
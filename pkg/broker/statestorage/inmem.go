@@ -0,0 +1,82 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statestorage
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// InmemStateStorage is a process-local StateBackend. It does not survive a
+// broker restart and does not coordinate across replicas, so it exists for
+// local development and tests rather than production use.
+type InmemStateStorage struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+func NewInmemBackend() StateBackend {
+	return &InmemStateStorage{values: make(map[string][]byte)}
+}
+
+// Configure is a no-op: the in-memory backend takes no configuration.
+func (s *InmemStateStorage) Configure(cfg Config) error {
+	return nil
+}
+
+// Put implements StateBackend.
+func (s *InmemStateStorage) Put(instanceID string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[instanceID] = raw
+	return nil
+}
+
+// Get implements StateBackend.
+func (s *InmemStateStorage) Get(instanceID string, out interface{}) error {
+	s.mu.RLock()
+	raw, ok := s.values[instanceID]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNotFound(instanceID)
+	}
+
+	return json.Unmarshal(raw, out)
+}
+
+// Delete implements StateBackend.
+func (s *InmemStateStorage) Delete(instanceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, instanceID)
+	return nil
+}
+
+// List implements StateBackend.
+func (s *InmemStateStorage) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.values))
+	for id := range s.values {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
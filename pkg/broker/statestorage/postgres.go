@@ -0,0 +1,115 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statestorage
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// PostgresStateStorage stores one row per instance in a single table
+// (instance_id text primary key, value jsonb), created by Configure if it
+// does not exist yet.
+type PostgresStateStorage struct {
+	db    *sql.DB
+	table string
+}
+
+func NewPostgresBackend() StateBackend {
+	return &PostgresStateStorage{}
+}
+
+// Configure reads "connectionString" and "table" out of cfg and ensures the
+// backing table exists.
+func (s *PostgresStateStorage) Configure(cfg Config) error {
+	db, err := sql.Open("postgres", cfg.string("connectionString"))
+	if err != nil {
+		return errors.Wrap(err, "cannot open Postgres connection")
+	}
+
+	table := cfg.string("table")
+	if table == "" {
+		table = "atlas_osb_state"
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` (
+		instance_id TEXT PRIMARY KEY,
+		value       JSONB NOT NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "cannot create Postgres state table")
+	}
+
+	s.db = db
+	s.table = table
+	return nil
+}
+
+// Put implements StateBackend.
+func (s *PostgresStateStorage) Put(instanceID string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO `+s.table+` (instance_id, value) VALUES ($1, $2)
+		 ON CONFLICT (instance_id) DO UPDATE SET value = EXCLUDED.value`,
+		instanceID, raw,
+	)
+	return errors.Wrap(err, "cannot upsert Postgres state row")
+}
+
+// Get implements StateBackend.
+func (s *PostgresStateStorage) Get(instanceID string, out interface{}) error {
+	var raw []byte
+	err := s.db.QueryRow(`SELECT value FROM `+s.table+` WHERE instance_id = $1`, instanceID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return ErrNotFound(instanceID)
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot query Postgres state row")
+	}
+
+	return json.Unmarshal(raw, out)
+}
+
+// Delete implements StateBackend.
+func (s *PostgresStateStorage) Delete(instanceID string) error {
+	_, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE instance_id = $1`, instanceID)
+	return errors.Wrap(err, "cannot delete Postgres state row")
+}
+
+// List implements StateBackend.
+func (s *PostgresStateStorage) List() ([]string, error) {
+	rows, err := s.db.Query(`SELECT instance_id FROM ` + s.table)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list Postgres state rows")
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
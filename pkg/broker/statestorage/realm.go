@@ -0,0 +1,150 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statestorage
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/broker/credentials"
+	"github.com/mongodb/mongodb-atlas-service-broker/pkg/mongodbrealm"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RealmStateStorage persists instance and binding metadata as values in a
+// Realm app's Value store, keyed by instance ID. It is the original and
+// still the default StateBackend, now registered under the "realm" name
+// instead of being constructed directly by Broker.
+type RealmStateStorage struct {
+	client  *mongodbrealm.Client
+	groupID string
+	appID   string
+	logger  *zap.SugaredLogger
+}
+
+// Get authenticates against Realm with key and returns a RealmStateStorage
+// scoped to the Atlas org's Realm app.
+func Get(key credentials.APIKey, atlasURL, realmURL string, logger *zap.SugaredLogger) (*RealmStateStorage, error) {
+	ctx := context.Background()
+
+	client, err := mongodbrealm.New(ctx, nil,
+		mongodbrealm.SetBaseURL(realmURL),
+		mongodbrealm.SetAPIAuth(ctx, key.PublicKey, key.PrivateKey),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create Realm client")
+	}
+
+	return &RealmStateStorage{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// NewRealmBackend is the factory registered with the backend registry. It
+// returns an unconfigured RealmStateStorage; Configure must be called before
+// use.
+func NewRealmBackend() StateBackend {
+	return &RealmStateStorage{}
+}
+
+// Configure wires the Atlas credentials and Realm app needed to reach the
+// Value store. cfg is expected to carry "publicKey", "privateKey",
+// "realmURL" and "groupID"/"appID", as produced by Broker from its
+// credentials and stateBackendConf.
+func (s *RealmStateStorage) Configure(cfg Config) error {
+	ctx := context.Background()
+
+	client, err := mongodbrealm.New(ctx, nil,
+		mongodbrealm.SetBaseURL(cfg.string("realmURL")),
+		mongodbrealm.SetAPIAuth(ctx, cfg.string("publicKey"), cfg.string("privateKey")),
+	)
+	if err != nil {
+		return errors.Wrap(err, "cannot create Realm client")
+	}
+
+	s.client = client
+	s.groupID = cfg.string("groupID")
+	s.appID = cfg.string("appID")
+	return nil
+}
+
+func (s *RealmStateStorage) valueName(instanceID string) string {
+	return "instance-" + instanceID
+}
+
+// Put implements StateBackend.
+func (s *RealmStateStorage) Put(instanceID string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal state value")
+	}
+
+	return errors.Wrap(
+		s.client.RealmValues.Put(context.Background(), s.groupID, s.appID, s.valueName(instanceID), raw),
+		"cannot write Realm value",
+	)
+}
+
+// Get implements StateBackend.
+func (s *RealmStateStorage) Get(instanceID string, out interface{}) error {
+	raw, err := s.client.RealmValues.Get(context.Background(), s.groupID, s.appID, s.valueName(instanceID))
+	if err != nil {
+		return ErrNotFound(instanceID)
+	}
+
+	return errors.Wrap(json.Unmarshal(raw, out), "cannot unmarshal state value")
+}
+
+// Delete implements StateBackend.
+func (s *RealmStateStorage) Delete(instanceID string) error {
+	return errors.Wrap(
+		s.client.RealmValues.Delete(context.Background(), s.groupID, s.appID, s.valueName(instanceID)),
+		"cannot delete Realm value",
+	)
+}
+
+// List implements StateBackend.
+func (s *RealmStateStorage) List() ([]string, error) {
+	names, err := s.client.RealmValues.List(context.Background(), s.groupID, s.appID)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list Realm values")
+	}
+
+	ids := make([]string, 0, len(names))
+	for _, n := range names {
+		ids = append(ids, strings.TrimPrefix(n, "instance-"))
+	}
+	return ids, nil
+}
+
+// deprecatedBackendShim adapts code that still constructs a
+// *RealmStateStorage directly via Get (the pre-registry behavior) so it
+// keeps compiling and behaving the same way against the StateBackend
+// interface Broker.getState now returns.
+//
+// Deprecated: call statestorageinit.Backend("realm") and Configure instead.
+type deprecatedBackendShim struct {
+	*RealmStateStorage
+}
+
+// NewDeprecatedShim wraps an already-authenticated RealmStateStorage (as
+// returned by Get) so callers not yet migrated to the registry can still
+// satisfy the StateBackend interface.
+func NewDeprecatedShim(s *RealmStateStorage) StateBackend {
+	return &deprecatedBackendShim{RealmStateStorage: s}
+}
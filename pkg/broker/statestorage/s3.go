@@ -0,0 +1,110 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statestorage
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+// S3StateStorage stores one object per instance under a configurable prefix
+// in a single S3 bucket.
+type S3StateStorage struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func NewS3Backend() StateBackend {
+	return &S3StateStorage{}
+}
+
+// Configure reads "bucket", "prefix" and "region" out of cfg.
+func (s *S3StateStorage) Configure(cfg Config) error {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(cfg.string("region"))})
+	if err != nil {
+		return errors.Wrap(err, "cannot create AWS session")
+	}
+
+	s.client = s3.New(sess)
+	s.bucket = cfg.string("bucket")
+	s.prefix = cfg.string("prefix")
+	return nil
+}
+
+func (s *S3StateStorage) key(instanceID string) string {
+	return s.prefix + instanceID + ".json"
+}
+
+// Put implements StateBackend.
+func (s *S3StateStorage) Put(instanceID string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(instanceID)),
+		Body:   bytes.NewReader(raw),
+	})
+	return errors.Wrap(err, "cannot put S3 object")
+}
+
+// Get implements StateBackend.
+func (s *S3StateStorage) Get(instanceID string, out interface{}) error {
+	obj, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(instanceID)),
+	})
+	if err != nil {
+		return ErrNotFound(instanceID)
+	}
+	defer obj.Body.Close()
+
+	return json.NewDecoder(obj.Body).Decode(out)
+}
+
+// Delete implements StateBackend.
+func (s *S3StateStorage) Delete(instanceID string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(instanceID)),
+	})
+	return errors.Wrap(err, "cannot delete S3 object")
+}
+
+// List implements StateBackend.
+func (s *S3StateStorage) List() ([]string, error) {
+	out, err := s.client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list S3 objects")
+	}
+
+	ids := make([]string, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		id := (*obj.Key)[len(s.prefix) : len(*obj.Key)-len(".json")]
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
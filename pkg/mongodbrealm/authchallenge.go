@@ -0,0 +1,103 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbrealm
+
+import (
+	"net/http"
+	"strings"
+)
+
+// authChallenge is one "WWW-Authenticate" challenge returned by the Realm
+// API, e.g. Bearer realm="...",service="...",scope="..." or
+// Basic realm="...".
+type authChallenge struct {
+	Scheme     string
+	Parameters map[string]string
+}
+
+// parseAuthHeader walks every WWW-Authenticate header on resp and parses it
+// into an authChallenge, per RFC 2617 (Basic) and RFC 6750 (Bearer).
+// Empty headers are skipped, and parameters without an "=" are dropped,
+// rather than returned as an error so one bad challenge doesn't hide the
+// others.
+func parseAuthHeader(resp *http.Response) []authChallenge {
+	var challenges []authChallenge
+
+	for _, header := range resp.Header[http.CanonicalHeaderKey("WWW-Authenticate")] {
+		scheme, params := parseChallengeHeader(header)
+		if scheme == "" {
+			continue
+		}
+		challenges = append(challenges, authChallenge{Scheme: scheme, Parameters: params})
+	}
+
+	return challenges
+}
+
+// parseChallengeHeader splits a single WWW-Authenticate value into its
+// scheme and comma-separated key="value" parameters.
+func parseChallengeHeader(header string) (scheme string, params map[string]string) {
+	header = strings.TrimSpace(header)
+	params = map[string]string{}
+
+	sp := strings.IndexByte(header, ' ')
+	if sp < 0 {
+		return strings.ToLower(header), params
+	}
+
+	scheme = strings.ToLower(header[:sp])
+	for _, kv := range splitChallengeParams(header[sp+1:]) {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+
+		eq := strings.IndexByte(kv, '=')
+		if eq < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(kv[:eq]))
+		val := strings.TrimSpace(kv[eq+1:])
+		val = strings.Trim(val, `"`)
+		params[key] = val
+	}
+
+	return scheme, params
+}
+
+// splitChallengeParams splits on commas that aren't inside a quoted string,
+// since challenge parameter values (e.g. scope="app:read,write") may themselves
+// contain characters that would otherwise look like separators.
+func splitChallengeParams(s string) []string {
+	var parts []string
+
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+
+	return parts
+}
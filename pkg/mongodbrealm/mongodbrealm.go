@@ -75,6 +75,23 @@ type Client struct {
 
 	auth *RealmAuth
 
+	// publicKey and privateKey are retained (rather than discarded after
+	// the initial login) so a later 401 carrying a scoped Bearer
+	// challenge can obtain a new token scoped to exactly what the server
+	// asked for, instead of replaying the original unscoped login.
+	publicKey  string
+	privateKey string
+
+	// tokenScope is the scope the current access token was obtained for,
+	// so a Bearer challenge asking for a different scope triggers a fresh
+	// login instead of a same-scope refresh.
+	tokenScope string
+
+	// authHandlers satisfy WWW-Authenticate challenges by scheme, keyed
+	// by the lowercased scheme name. Do picks the first one matching a
+	// challenge the server returned.
+	authHandlers map[string]authHandler
+
 	onRequestCompleted RequestCompletionCallback
 }
 
@@ -168,6 +185,14 @@ func NewClient(httpClient *http.Client) *Client {
 	c.RealmApps = &RealmAppsServiceOp{Client: c}
 	c.RealmValues = &RealmValuesServiceOp{Client: c}
 
+	// Bearer is pre-registered so SetAPIAuth's login flow keeps working
+	// unchanged; Basic is available for any Realm endpoint that's
+	// configured to challenge for it instead.
+	c.authHandlers = map[string]authHandler{
+		"bearer": &bearerHandler{client: c},
+		"basic":  &basicHandler{client: c},
+	}
+
 	return c
 }
 
@@ -214,7 +239,9 @@ func SetUserAgent(ua string) ClientOpt {
 
 func SetAPIAuth(ctx context.Context, pub string, priv string) ClientOpt {
 	return func(c *Client) error {
-		return c.obtainToken(ctx, pub, priv)
+		c.publicKey = pub
+		c.privateKey = priv
+		return c.obtainToken(ctx, pub, priv, "")
 	}
 }
 
@@ -234,11 +261,18 @@ func (c *Client) refreshToken(ctx context.Context) error {
 	return errors.Wrap(CheckResponse(resp.Response), "unexpected response")
 }
 
-func (c *Client) obtainToken(ctx context.Context, publicKey string, privateKey string) error {
+// obtainToken logs in with publicKey/privateKey, optionally asking Realm to
+// scope the resulting access token to scope (as requested by a Bearer
+// challenge's "scope" parameter). An empty scope requests the default,
+// unscoped token.
+func (c *Client) obtainToken(ctx context.Context, publicKey string, privateKey string, scope string) error {
 	data := map[string]interface{}{
 		"username": publicKey,
 		"apiKey":   privateKey,
 	}
+	if scope != "" {
+		data["scope"] = scope
+	}
 
 	loginReq, err := c.NewRequest(ctx, http.MethodPost, realmLoginPath, data)
 	if err != nil {
@@ -250,6 +284,8 @@ func (c *Client) obtainToken(ctx context.Context, publicKey string, privateKey s
 		return errors.Wrapf(err, "cannot do login request (public key %q)", publicKey)
 	}
 
+	c.tokenScope = scope
+
 	return nil
 }
 
@@ -323,26 +359,54 @@ func (c *Client) OnRequestCompleted(rc RequestCompletionCallback) {
 	c.onRequestCompleted = rc
 }
 
+// Do sends req, authenticated with the client's current access token. On a
+// 401 it parses the WWW-Authenticate challenges the server returned, picks
+// the first one with a registered authHandler, and retries exactly once
+// after satisfying it. A challenge carrying an RFC 6750 "error" parameter
+// (e.g. insufficient_scope) is surfaced as a typed *ErrorResponse instead
+// of being retried, since retrying with the same credentials would just
+// fail the same way.
 func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, error) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.auth.AccessToken))
+
 	resp, err := c.do(ctx, req, v)
-	if err != nil {
-		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
-			_ = resp.Body.Close()
+	if err == nil {
+		return resp, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return nil, err
+	}
 
-			err = c.refreshToken(ctx)
-			if err != nil {
-				return nil, errors.Wrap(err, "cannot refresh auth token")
-			}
+	challenge, handler := c.selectAuthHandler(parseAuthHeader(resp.Response))
+	if handler == nil {
+		return nil, errors.Wrap(err, "server returned 401 with no satisfiable WWW-Authenticate challenge")
+	}
 
-			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.auth.AccessToken))
-			return c.do(ctx, req, v)
+	if reason := challenge.Parameters["error"]; reason != "" {
+		return nil, &ErrorResponse{
+			Response:  resp.Response,
+			ErrorCode: resp.StatusCode,
+			Reason:    reason,
+			Detail:    challenge.Parameters["error_description"],
 		}
+	}
 
-		return nil, err
+	if err := handler.AuthorizeRequest(ctx, req, challenge.Parameters); err != nil {
+		return nil, errors.Wrap(err, "cannot satisfy auth challenge")
 	}
 
-	return resp, nil
+	return c.do(ctx, req, v)
+}
+
+// selectAuthHandler returns the first challenge with a registered handler,
+// in the order the server sent them.
+func (c *Client) selectAuthHandler(challenges []authChallenge) (authChallenge, authHandler) {
+	for _, challenge := range challenges {
+		if h, ok := c.authHandlers[challenge.Scheme]; ok {
+			return challenge, h
+		}
+	}
+	return authChallenge{}, nil
 }
 
 // Do sends an API request and returns the API response. The API response is JSON decoded and stored in the value
@@ -0,0 +1,87 @@
+// Copyright 2020 MongoDB Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbrealm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// authHandler satisfies one WWW-Authenticate challenge scheme by mutating
+// req so the retried request carries the credentials that scheme expects.
+type authHandler interface {
+	// Scheme is the lowercased challenge scheme this handler satisfies,
+	// e.g. "bearer" or "basic".
+	Scheme() string
+
+	// AuthorizeRequest prepares req to satisfy a challenge with the given
+	// parameters.
+	AuthorizeRequest(ctx context.Context, req *http.Request, params map[string]string) error
+}
+
+// bearerHandler satisfies a Bearer challenge by obtaining (or refreshing) a
+// token scoped to the "scope" parameter the server asked for, so a 401 from
+// an endpoint that lives in a different Realm app doesn't get silently
+// retried with a token that was never going to work for it.
+type bearerHandler struct {
+	client *Client
+}
+
+func (h *bearerHandler) Scheme() string { return "bearer" }
+
+func (h *bearerHandler) AuthorizeRequest(ctx context.Context, req *http.Request, params map[string]string) error {
+	if err := h.client.obtainOrRefreshToken(ctx, params["scope"]); err != nil {
+		return errors.Wrap(err, "cannot satisfy bearer challenge")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+h.client.auth.AccessToken)
+	return nil
+}
+
+// basicHandler satisfies a Basic challenge using the public/private API key
+// pair the client was originally configured with.
+type basicHandler struct {
+	client *Client
+}
+
+func (h *basicHandler) Scheme() string { return "basic" }
+
+func (h *basicHandler) AuthorizeRequest(ctx context.Context, req *http.Request, params map[string]string) error {
+	if h.client.publicKey == "" {
+		return errors.New("no API key configured to satisfy basic challenge")
+	}
+
+	req.SetBasicAuth(h.client.publicKey, h.client.privateKey)
+	return nil
+}
+
+// obtainOrRefreshToken gets a new access token scoped to scope. If the
+// client already has a refresh token *and* that token was obtained for the
+// same scope being requested now, refreshing it is tried first; a refresh
+// preserves whatever scope the token already has, so it can't satisfy a
+// challenge asking for a different one. Otherwise (a scope change, no
+// refresh token, or the refresh itself being rejected) the client logs in
+// again from its API key, requesting scope directly.
+func (c *Client) obtainOrRefreshToken(ctx context.Context, scope string) error {
+	if c.auth.RefreshToken != "" && c.tokenScope == scope {
+		if err := c.refreshToken(ctx); err == nil {
+			return nil
+		}
+	}
+
+	return c.obtainToken(ctx, c.publicKey, c.privateKey, scope)
+}